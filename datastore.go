@@ -0,0 +1,193 @@
+package mbserver
+
+// DataStore abstracts the memory backing a Modbus slave's four register
+// types. The built-in function handlers (ReadCoils, WriteHoldingRegister,
+// etc.) read and write through this interface rather than touching a
+// server's slices directly, so a Server can be backed by anything from a
+// plain in-memory array to a callback that samples live device state.
+type DataStore interface {
+	ReadCoils(address, quantity uint16) ([]byte, error)
+	WriteCoils(address uint16, values []byte) error
+	ReadDiscreteInputs(address, quantity uint16) ([]byte, error)
+	ReadHoldingRegisters(address, quantity uint16) ([]uint16, error)
+	WriteHoldingRegisters(address uint16, values []uint16) error
+	ReadInputRegisters(address, quantity uint16) ([]uint16, error)
+}
+
+// MemoryDataStore is the default DataStore: four fixed-size in-memory
+// arrays, identical to the server's historical behavior. NewServer wires
+// a MemoryDataStore to a server's exported DiscreteInputs/Coils/
+// HoldingRegisters/InputRegisters fields so existing code that mutates
+// those slices directly keeps working unchanged.
+type MemoryDataStore struct {
+	DiscreteInputs   []byte
+	Coils            []byte
+	HoldingRegisters []uint16
+	InputRegisters   []uint16
+}
+
+// NewMemoryDataStore allocates a MemoryDataStore with the standard
+// 65536-address Modbus memory maps.
+func NewMemoryDataStore() *MemoryDataStore {
+	return &MemoryDataStore{
+		DiscreteInputs:   make([]byte, 65536),
+		Coils:            make([]byte, 65536),
+		HoldingRegisters: make([]uint16, 65536),
+		InputRegisters:   make([]uint16, 65536),
+	}
+}
+
+func (d *MemoryDataStore) ReadCoils(address, quantity uint16) ([]byte, error) {
+	return d.Coils[address : address+quantity], nil
+}
+
+func (d *MemoryDataStore) WriteCoils(address uint16, values []byte) error {
+	copy(d.Coils[address:], values)
+	return nil
+}
+
+func (d *MemoryDataStore) ReadDiscreteInputs(address, quantity uint16) ([]byte, error) {
+	return d.DiscreteInputs[address : address+quantity], nil
+}
+
+func (d *MemoryDataStore) ReadHoldingRegisters(address, quantity uint16) ([]uint16, error) {
+	return d.HoldingRegisters[address : address+quantity], nil
+}
+
+func (d *MemoryDataStore) WriteHoldingRegisters(address uint16, values []uint16) error {
+	copy(d.HoldingRegisters[address:], values)
+	return nil
+}
+
+func (d *MemoryDataStore) ReadInputRegisters(address, quantity uint16) ([]uint16, error) {
+	return d.InputRegisters[address : address+quantity], nil
+}
+
+// CoilRange maps a span of Quantity coil/discrete-input addresses,
+// starting at Address, to a getter/setter pair so a CallbackDataStore can
+// expose live values (e.g. a generator's running status) instead of a
+// plain array.
+type CoilRange struct {
+	Address  uint16
+	Quantity uint16
+	Get      func(offset uint16) (bool, error)
+	Set      func(offset uint16, value bool) error
+}
+
+// RegisterRange maps a span of Quantity holding/input-register addresses,
+// starting at Address, to a getter/setter pair so a CallbackDataStore can
+// expose a computed or sampled value (e.g. a live temperature reading).
+type RegisterRange struct {
+	Address  uint16
+	Quantity uint16
+	Get      func(offset uint16) (uint16, error)
+	Set      func(offset uint16, value uint16) error
+}
+
+// CallbackDataStore is a DataStore whose address ranges are backed by
+// user-supplied getter/setter callbacks, letting a handler expose live
+// device values without replacing every function handler via
+// RegisterFunctionHandler. Addresses falling outside every registered
+// range read as zero and ignore writes, matching a blank MemoryDataStore.
+type CallbackDataStore struct {
+	Coils            []CoilRange
+	DiscreteInputs   []CoilRange
+	HoldingRegisters []RegisterRange
+	InputRegisters   []RegisterRange
+}
+
+func (d *CallbackDataStore) readCoilRange(ranges []CoilRange, address, quantity uint16) ([]byte, error) {
+	out := make([]byte, quantity)
+	for i := uint16(0); i < quantity; i++ {
+		r := findCoilRange(ranges, address+i)
+		if r == nil {
+			continue
+		}
+		value, err := r.Get(address + i - r.Address)
+		if err != nil {
+			return nil, err
+		}
+		if value {
+			out[i] = 1
+		}
+	}
+	return out, nil
+}
+
+func (d *CallbackDataStore) ReadCoils(address, quantity uint16) ([]byte, error) {
+	return d.readCoilRange(d.Coils, address, quantity)
+}
+
+func (d *CallbackDataStore) WriteCoils(address uint16, values []byte) error {
+	for i, value := range values {
+		r := findCoilRange(d.Coils, address+uint16(i))
+		if r == nil || r.Set == nil {
+			continue
+		}
+		if err := r.Set(address+uint16(i)-r.Address, value != 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CallbackDataStore) ReadDiscreteInputs(address, quantity uint16) ([]byte, error) {
+	return d.readCoilRange(d.DiscreteInputs, address, quantity)
+}
+
+func (d *CallbackDataStore) readRegisterRange(ranges []RegisterRange, address, quantity uint16) ([]uint16, error) {
+	out := make([]uint16, quantity)
+	for i := uint16(0); i < quantity; i++ {
+		r := findRegisterRange(ranges, address+i)
+		if r == nil {
+			continue
+		}
+		value, err := r.Get(address + i - r.Address)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = value
+	}
+	return out, nil
+}
+
+func (d *CallbackDataStore) ReadHoldingRegisters(address, quantity uint16) ([]uint16, error) {
+	return d.readRegisterRange(d.HoldingRegisters, address, quantity)
+}
+
+func (d *CallbackDataStore) WriteHoldingRegisters(address uint16, values []uint16) error {
+	for i, value := range values {
+		r := findRegisterRange(d.HoldingRegisters, address+uint16(i))
+		if r == nil || r.Set == nil {
+			continue
+		}
+		if err := r.Set(address+uint16(i)-r.Address, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *CallbackDataStore) ReadInputRegisters(address, quantity uint16) ([]uint16, error) {
+	return d.readRegisterRange(d.InputRegisters, address, quantity)
+}
+
+func findCoilRange(ranges []CoilRange, address uint16) *CoilRange {
+	for i := range ranges {
+		start, end := int(ranges[i].Address), int(ranges[i].Address)+int(ranges[i].Quantity)
+		if int(address) >= start && int(address) < end {
+			return &ranges[i]
+		}
+	}
+	return nil
+}
+
+func findRegisterRange(ranges []RegisterRange, address uint16) *RegisterRange {
+	for i := range ranges {
+		start, end := int(ranges[i].Address), int(ranges[i].Address)+int(ranges[i].Quantity)
+		if int(address) >= start && int(address) < end {
+			return &ranges[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package mbserver
+
+import "testing"
+
+func TestCallbackDataStoreHoldingRegisterRanges(t *testing.T) {
+	const lowValue, highValue uint16 = 111, 222
+
+	store := &CallbackDataStore{
+		HoldingRegisters: []RegisterRange{
+			{
+				Address:  0,
+				Quantity: 10,
+				Get:      func(offset uint16) (uint16, error) { return lowValue, nil },
+			},
+			{
+				Address:  100,
+				Quantity: 10,
+				Get:      func(offset uint16) (uint16, error) { return highValue, nil },
+			},
+		},
+	}
+
+	got, err := store.ReadHoldingRegisters(100, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters(100, 1): %v", err)
+	}
+	if got[0] != highValue {
+		t.Fatalf("ReadHoldingRegisters(100, 1) = %v, want [%d]; second range is unreachable", got, highValue)
+	}
+
+	got, err = store.ReadHoldingRegisters(0, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters(0, 1): %v", err)
+	}
+	if got[0] != lowValue {
+		t.Fatalf("ReadHoldingRegisters(0, 1) = %v, want [%d]", got, lowValue)
+	}
+}
+
+func TestCallbackDataStoreRangeNearAddressSpaceTop(t *testing.T) {
+	const value uint16 = 42
+
+	store := &CallbackDataStore{
+		HoldingRegisters: []RegisterRange{
+			{
+				Address:  65530,
+				Quantity: 10,
+				Get:      func(offset uint16) (uint16, error) { return value, nil },
+			},
+		},
+	}
+
+	got, err := store.ReadHoldingRegisters(65535, 1)
+	if err != nil {
+		t.Fatalf("ReadHoldingRegisters(65535, 1): %v", err)
+	}
+	if got[0] != value {
+		t.Fatalf("ReadHoldingRegisters(65535, 1) = %v, want [%d]; Address+Quantity must not overflow uint16", got, value)
+	}
+}
@@ -0,0 +1,35 @@
+package mbserver
+
+import "encoding/binary"
+
+// readRequest decodes the address/quantity pair that prefixes every
+// Modbus read request's data.
+func readRequest(frame Framer) (address, quantity uint16) {
+	data := frame.GetData()
+	return binary.BigEndian.Uint16(data[0:2]), binary.BigEndian.Uint16(data[2:4])
+}
+
+// encodeRegisters packs register values into a Modbus byte-count-
+// prefixed response payload.
+func encodeRegisters(registers []uint16) []byte {
+	data := make([]byte, 1+2*len(registers))
+	data[0] = byte(2 * len(registers))
+	for i, value := range registers {
+		binary.BigEndian.PutUint16(data[1+2*i:3+2*i], value)
+	}
+	return data
+}
+
+// encodeCoils packs coil/discrete-input values into a Modbus byte-count-
+// prefixed, bit-packed response payload.
+func encodeCoils(values []byte) []byte {
+	byteCount := (len(values) + 7) / 8
+	data := make([]byte, 1+byteCount)
+	data[0] = byte(byteCount)
+	for i, value := range values {
+		if value != 0 {
+			data[1+i/8] |= 1 << uint(i%8)
+		}
+	}
+	return data
+}
@@ -0,0 +1,58 @@
+package mbserver
+
+import "encoding/binary"
+
+// ReadCoils implements Modbus function code 1 against s.DataStore.
+func ReadCoils(s *Server, frame Framer) ([]byte, *Exception) {
+	address, quantity := readRequest(frame)
+
+	coils, err := s.DataStore.ReadCoils(address, quantity)
+	if err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return encodeCoils(coils), &Success
+}
+
+// WriteSingleCoil implements Modbus function code 5 against s.DataStore.
+func WriteSingleCoil(s *Server, frame Framer) ([]byte, *Exception) {
+	data := frame.GetData()
+	address := binary.BigEndian.Uint16(data[0:2])
+	value := binary.BigEndian.Uint16(data[2:4])
+
+	var coil byte
+	switch value {
+	case 0xFF00:
+		coil = 1
+	case 0x0000:
+		coil = 0
+	default:
+		return nil, &IllegalDataValue
+	}
+
+	if err := s.DataStore.WriteCoils(address, []byte{coil}); err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return data, &Success
+}
+
+// WriteMultipleCoils implements Modbus function code 15 against
+// s.DataStore.
+func WriteMultipleCoils(s *Server, frame Framer) ([]byte, *Exception) {
+	data := frame.GetData()
+	address := binary.BigEndian.Uint16(data[0:2])
+	quantity := binary.BigEndian.Uint16(data[2:4])
+	byteCount := int(data[4])
+	packed := data[5 : 5+byteCount]
+
+	coils := make([]byte, quantity)
+	for i := range coils {
+		if packed[i/8]&(1<<uint(i%8)) != 0 {
+			coils[i] = 1
+		}
+	}
+
+	if err := s.DataStore.WriteCoils(address, coils); err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return data[0:4], &Success
+}
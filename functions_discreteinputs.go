@@ -0,0 +1,13 @@
+package mbserver
+
+// ReadDiscreteInputs implements Modbus function code 2 against
+// s.DataStore.
+func ReadDiscreteInputs(s *Server, frame Framer) ([]byte, *Exception) {
+	address, quantity := readRequest(frame)
+
+	inputs, err := s.DataStore.ReadDiscreteInputs(address, quantity)
+	if err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return encodeCoils(inputs), &Success
+}
@@ -0,0 +1,46 @@
+package mbserver
+
+import "encoding/binary"
+
+// ReadHoldingRegisters implements Modbus function code 3 against
+// s.DataStore.
+func ReadHoldingRegisters(s *Server, frame Framer) ([]byte, *Exception) {
+	address, quantity := readRequest(frame)
+
+	registers, err := s.DataStore.ReadHoldingRegisters(address, quantity)
+	if err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return encodeRegisters(registers), &Success
+}
+
+// WriteHoldingRegister implements Modbus function code 6 against
+// s.DataStore.
+func WriteHoldingRegister(s *Server, frame Framer) ([]byte, *Exception) {
+	data := frame.GetData()
+	address := binary.BigEndian.Uint16(data[0:2])
+	value := binary.BigEndian.Uint16(data[2:4])
+
+	if err := s.DataStore.WriteHoldingRegisters(address, []uint16{value}); err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return data, &Success
+}
+
+// WriteHoldingRegisters implements Modbus function code 16 against
+// s.DataStore.
+func WriteHoldingRegisters(s *Server, frame Framer) ([]byte, *Exception) {
+	data := frame.GetData()
+	address := binary.BigEndian.Uint16(data[0:2])
+	quantity := binary.BigEndian.Uint16(data[2:4])
+
+	values := make([]uint16, quantity)
+	for i := range values {
+		values[i] = binary.BigEndian.Uint16(data[5+2*i : 7+2*i])
+	}
+
+	if err := s.DataStore.WriteHoldingRegisters(address, values); err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return data[0:4], &Success
+}
@@ -0,0 +1,13 @@
+package mbserver
+
+// ReadInputRegisters implements Modbus function code 4 against
+// s.DataStore.
+func ReadInputRegisters(s *Server, frame Framer) ([]byte, *Exception) {
+	address, quantity := readRequest(frame)
+
+	registers, err := s.DataStore.ReadInputRegisters(address, quantity)
+	if err != nil {
+		return nil, &IllegalDataAddress
+	}
+	return encodeRegisters(registers), &Success
+}
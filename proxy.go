@@ -0,0 +1,267 @@
+package mbserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/goburrow/serial"
+)
+
+// Upstream is a single shared Modbus link (serial RTU bus or TCP slave)
+// that a gateway-mode Server multiplexes proxied requests onto.
+type Upstream interface {
+	// Transact sends a single PDU addressed to unitID over the upstream
+	// link and returns the matching response PDU.
+	Transact(unitID uint8, pdu []byte) ([]byte, error)
+
+	// Close releases the underlying port or connection.
+	Close() error
+}
+
+// ProxyFilter restricts which unit IDs and function codes a gateway will
+// forward upstream. A nil map allows everything for that dimension.
+type ProxyFilter struct {
+	AllowUnitIDs   map[uint8]bool
+	AllowFunctions map[uint8]bool
+}
+
+func (f *ProxyFilter) allows(unitID, function uint8) bool {
+	if f == nil {
+		return true
+	}
+	if f.AllowUnitIDs != nil && !f.AllowUnitIDs[unitID] {
+		return false
+	}
+	if f.AllowFunctions != nil && !f.AllowFunctions[function] {
+		return false
+	}
+	return true
+}
+
+// EnableProxy switches the server into gateway mode. Once set, handle()
+// forwards every request to upstream instead of dispatching to the local
+// function table, keyed by unit ID, and relays the reply back to the
+// originating connection. filter may be nil to allow everything.
+func (s *Server) EnableProxy(upstream Upstream, filter *ProxyFilter) {
+	s.upstream = upstream
+	s.proxyFilter = filter
+}
+
+// proxyHandle forwards request upstream and returns the relayed (or
+// locally-generated exception) response. Access to upstream is
+// serialized so responses can't be interleaved across connections.
+func (s *Server) proxyHandle(request *Request) Framer {
+	response := request.frame.Copy()
+	unitID := request.frame.GetAddress()
+	function := request.frame.GetFunction()
+
+	if !s.proxyFilter.allows(unitID, function) {
+		response.SetException(&GatewayPathUnavailable)
+		return response
+	}
+
+	// Transact speaks in full PDUs (function code + data), so prefix the
+	// function code request.frame.GetData() leaves off.
+	pdu := append([]byte{function}, request.frame.GetData()...)
+
+	s.upstreamMu.Lock()
+	reply, err := s.upstream.Transact(unitID, pdu)
+	s.upstreamMu.Unlock()
+	if err != nil {
+		if s.Debug {
+			log.Printf("proxy transaction failed: %v\n", err)
+		}
+		response.SetException(&GatewayTargetDeviceFailedToRespond)
+		return response
+	}
+	if len(reply) == 0 {
+		response.SetException(&GatewayTargetDeviceFailedToRespond)
+		return response
+	}
+
+	// reply is itself a full PDU; strip the function code back off
+	// before handing the data to the response frame.
+	response.SetData(reply[1:])
+	return response
+}
+
+// rtuUpstream proxies requests onto a shared Modbus RTU serial bus.
+type rtuUpstream struct {
+	port    serial.Port
+	mu      sync.Mutex
+	timeout time.Duration
+}
+
+// NewRTUUpstream wraps an already-opened serial port as a gateway
+// upstream, framing each proxied request as Modbus RTU and waiting up to
+// 1 second for the matching reply. serial.Port exposes no read deadline
+// of its own, so the wait is enforced by racing the blocking Read
+// against a timer rather than by the port itself.
+func NewRTUUpstream(port serial.Port) Upstream {
+	return &rtuUpstream{port: port, timeout: 1 * time.Second}
+}
+
+type rtuReadResult struct {
+	n   int
+	buf []byte
+	err error
+}
+
+func (u *rtuUpstream) Transact(unitID uint8, pdu []byte) ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	adu := append([]byte{unitID}, pdu...)
+	adu = append(adu, crc16(adu)...)
+
+	if _, err := u.port.Write(adu); err != nil {
+		return nil, err
+	}
+
+	// port.Read has no deadline of its own, so bound the wait with a
+	// timer instead; the read goroutine is abandoned (not canceled) if
+	// it times out, since serial.Port gives us no way to interrupt it.
+	read := make(chan rtuReadResult, 1)
+	go func() {
+		buf := make([]byte, 256)
+		n, err := u.port.Read(buf)
+		read <- rtuReadResult{n, buf, err}
+	}()
+
+	var result rtuReadResult
+	select {
+	case result = <-read:
+	case <-time.After(u.timeout):
+		return nil, errors.New("mbserver: timed out waiting for RTU upstream reply")
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	if result.n < 4 {
+		return nil, errors.New("mbserver: short RTU reply from upstream")
+	}
+	reply := result.buf[:result.n]
+
+	got := reply[:len(reply)-2]
+	want := crc16(got)
+	if reply[len(reply)-2] != want[0] || reply[len(reply)-1] != want[1] {
+		return nil, errors.New("mbserver: bad CRC from upstream")
+	}
+	if got[0] != unitID {
+		return nil, errors.New("mbserver: unit ID mismatch from upstream")
+	}
+	return got[1:], nil
+}
+
+func (u *rtuUpstream) Close() error {
+	return u.port.Close()
+}
+
+// tcpUpstream proxies requests onto a single upstream Modbus/TCP slave.
+type tcpUpstream struct {
+	addr    string
+	mu      sync.Mutex
+	conn    net.Conn
+	nextTxn uint16
+	timeout time.Duration
+}
+
+// NewTCPUpstream dials (lazily, on first use) an upstream Modbus/TCP
+// slave and reuses the connection across proxied requests, waiting up to
+// 1 second for each matching reply.
+func NewTCPUpstream(addr string) Upstream {
+	return &tcpUpstream{addr: addr, timeout: 1 * time.Second}
+}
+
+func (u *tcpUpstream) Transact(unitID uint8, pdu []byte) ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.conn == nil {
+		conn, err := net.Dial("tcp", u.addr)
+		if err != nil {
+			return nil, err
+		}
+		u.conn = conn
+	}
+
+	u.nextTxn++
+	txn := u.nextTxn
+
+	mbap := make([]byte, 7, 7+len(pdu))
+	binary.BigEndian.PutUint16(mbap[0:2], txn)
+	binary.BigEndian.PutUint16(mbap[2:4], 0)
+	binary.BigEndian.PutUint16(mbap[4:6], uint16(len(pdu)+1))
+	mbap[6] = unitID
+	adu := append(mbap, pdu...)
+
+	u.conn.SetDeadline(time.Now().Add(u.timeout))
+	if _, err := u.conn.Write(adu); err != nil {
+		u.conn.Close()
+		u.conn = nil
+		return nil, err
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(u.conn, header); err != nil {
+		u.conn.Close()
+		u.conn = nil
+		return nil, err
+	}
+	length := binary.BigEndian.Uint16(header[4:6])
+	if binary.BigEndian.Uint16(header[0:2]) != txn {
+		// The reply body is still sitting unread on the wire; drain it
+		// (best effort) before dropping the connection so a future
+		// caller can't mistake it for the next reply's header.
+		if length > 0 {
+			io.CopyN(io.Discard, u.conn, int64(length-1))
+		}
+		u.conn.Close()
+		u.conn = nil
+		return nil, errors.New("mbserver: transaction ID mismatch from upstream")
+	}
+	if length == 0 {
+		u.conn.Close()
+		u.conn = nil
+		return nil, errors.New("mbserver: empty upstream reply")
+	}
+
+	body := make([]byte, length-1)
+	if _, err := io.ReadFull(u.conn, body); err != nil {
+		u.conn.Close()
+		u.conn = nil
+		return nil, err
+	}
+	return body, nil
+}
+
+func (u *tcpUpstream) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn != nil {
+		return u.conn.Close()
+	}
+	return nil
+}
+
+// crc16 computes the Modbus RTU CRC for adu, returned low byte first.
+func crc16(adu []byte) []byte {
+	var crc uint16 = 0xFFFF
+	for _, b := range adu {
+		crc ^= uint16(b)
+		for i := 0; i < 8; i++ {
+			if crc&1 != 0 {
+				crc >>= 1
+				crc ^= 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return []byte{byte(crc), byte(crc >> 8)}
+}
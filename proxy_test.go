@@ -0,0 +1,65 @@
+package mbserver
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fakeFrame is a minimal Framer stand-in used to drive proxyHandle
+// without a real TCP/RTU frame.
+type fakeFrame struct {
+	unitID   uint8
+	function uint8
+	data     []byte
+}
+
+func (f *fakeFrame) Copy() Framer {
+	c := *f
+	return &c
+}
+func (f *fakeFrame) Bytes() []byte             { return append([]byte{f.function}, f.data...) }
+func (f *fakeFrame) GetFunction() uint8        { return f.function }
+func (f *fakeFrame) GetData() []byte           { return f.data }
+func (f *fakeFrame) SetData(data []byte)       { f.data = data }
+func (f *fakeFrame) GetAddress() uint8         { return f.unitID }
+func (f *fakeFrame) SetException(e *Exception) {}
+
+// fakeUpstream records the PDU it was asked to forward and returns a
+// canned reply PDU (function code + data), so the test can assert the
+// function code survives both directions of the proxy.
+type fakeUpstream struct {
+	gotUnitID uint8
+	gotPDU    []byte
+	reply     []byte
+}
+
+func (u *fakeUpstream) Transact(unitID uint8, pdu []byte) ([]byte, error) {
+	u.gotUnitID = unitID
+	u.gotPDU = append([]byte(nil), pdu...)
+	return u.reply, nil
+}
+func (u *fakeUpstream) Close() error { return nil }
+
+func TestProxyHandleRoundTrip(t *testing.T) {
+	up := &fakeUpstream{reply: []byte{0x03, 0xAA, 0xBB}}
+	s := &Server{upstream: up}
+
+	frame := &fakeFrame{unitID: 7, function: 0x03, data: []byte{0x00, 0x01, 0x00, 0x02}}
+	request := &Request{frame: frame}
+
+	response := s.handle(request)
+
+	wantPDU := []byte{0x03, 0x00, 0x01, 0x00, 0x02}
+	if !reflect.DeepEqual(up.gotPDU, wantPDU) {
+		t.Fatalf("upstream got PDU %v, want %v (function code must be prefixed)", up.gotPDU, wantPDU)
+	}
+	if up.gotUnitID != 7 {
+		t.Fatalf("upstream got unit ID %d, want 7", up.gotUnitID)
+	}
+
+	got := response.(*fakeFrame).GetData()
+	want := []byte{0xAA, 0xBB}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("response data = %v, want %v (function code must be stripped from reply)", got, want)
+	}
+}
@@ -0,0 +1,55 @@
+package mbserver
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// IPRateLimiter caps how many new connections a single source IP may
+// open within a sliding time window, so a misbehaving or malicious TCP
+// client can't exhaust the server's connection budget by itself.
+type IPRateLimiter struct {
+	// Limit is the maximum number of connections allowed per IP per
+	// Window.
+	Limit int
+	// Window is the sliding duration over which Limit applies.
+	Window time.Duration
+
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+// NewIPRateLimiter returns a limiter allowing at most limit new
+// connections per IP within window.
+func NewIPRateLimiter(limit int, window time.Duration) *IPRateLimiter {
+	return &IPRateLimiter{Limit: limit, Window: window, hits: make(map[string][]time.Time)}
+}
+
+// Allow reports whether addr may open another connection now. If so, the
+// attempt is recorded against addr's host for future calls.
+func (l *IPRateLimiter) Allow(addr net.Addr) bool {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		host = addr.String()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cutoff := time.Now().Add(-l.Window)
+	recent := l.hits[host][:0]
+	for _, t := range l.hits[host] {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+
+	if len(recent) >= l.Limit {
+		l.hits[host] = recent
+		return false
+	}
+
+	l.hits[host] = append(recent, time.Now())
+	return true
+}
@@ -2,9 +2,11 @@
 package mbserver
 
 import (
+	"context"
 	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/goburrow/serial"
 )
@@ -19,6 +21,7 @@ type Server struct {
 	portsCloseChan          chan struct{}
 	requestChan             chan *Request
 	function                [256](func(*Server, Framer) ([]byte, *Exception))
+	DataStore               DataStore
 	DiscreteInputs          []byte
 	Coils                   []byte
 	HoldingRegisters        []uint16
@@ -29,6 +32,35 @@ type Server struct {
 	ResponseSentEvent       [](func(io.ReadWriteCloser, Framer))
 	ServerStartedEvent      [](func(net.Listener))
 	ServerStoppedEvent      [](func(net.Listener))
+	upstream                Upstream
+	upstreamMu              sync.Mutex
+	proxyFilter             *ProxyFilter
+	ctx                     context.Context
+	cancel                  context.CancelFunc
+	conns                   sync.Map
+	connsWG                 sync.WaitGroup
+	connCount               int32
+	// MaxConnections caps concurrent TCP connections; 0 means unlimited.
+	MaxConnections int
+	// ReadTimeout bounds how long a read may block once a connection is
+	// accepted; 0 means no deadline.
+	ReadTimeout time.Duration
+	// IdleTimeout bounds how long a connection may sit between requests
+	// before it is closed; 0 means no deadline.
+	IdleTimeout time.Duration
+	// DrainTimeout bounds how long Close waits for connection goroutines
+	// to exit after canceling them before closing any connections that
+	// remain. Requests already handed to the handler are not waited for.
+	DrainTimeout time.Duration
+	// RateLimiter, if set, caps how many new connections a single source
+	// IP may open in a sliding window.
+	RateLimiter   *IPRateLimiter
+	packetConns   []net.PacketConn
+	udpConns      sync.Map
+	roleExtractor RoleOIDExtractor
+	connRoles     sync.Map
+	units         sync.Map
+	unitCount     int32
 }
 
 // Request contains the connection and Modbus frame.
@@ -41,11 +73,15 @@ type Request struct {
 func NewServer() *Server {
 	s := &Server{}
 
-	// Allocate Modbus memory maps.
-	s.DiscreteInputs = make([]byte, 65536)
-	s.Coils = make([]byte, 65536)
-	s.HoldingRegisters = make([]uint16, 65536)
-	s.InputRegisters = make([]uint16, 65536)
+	// Allocate Modbus memory maps and wire the default in-memory
+	// DataStore to the exported slices so existing code that mutates
+	// them directly keeps working unchanged.
+	memory := NewMemoryDataStore()
+	s.DataStore = memory
+	s.DiscreteInputs = memory.DiscreteInputs
+	s.Coils = memory.Coils
+	s.HoldingRegisters = memory.HoldingRegisters
+	s.InputRegisters = memory.InputRegisters
 
 	// Add default functions.
 	s.function[1] = ReadCoils
@@ -59,6 +95,8 @@ func NewServer() *Server {
 
 	s.requestChan = make(chan *Request)
 	s.portsCloseChan = make(chan struct{})
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.DrainTimeout = 5 * time.Second
 
 	go s.handler()
 
@@ -71,6 +109,20 @@ func (s *Server) RegisterFunctionHandler(funcCode uint8, function func(*Server,
 }
 
 func (s *Server) handle(request *Request) Framer {
+	target, ok := s.unitFor(request.frame.GetAddress())
+	if !ok {
+		response := request.frame.Copy()
+		response.SetException(&GatewayTargetDeviceFailedToRespond)
+		return response
+	}
+	if target != s {
+		return target.handle(request)
+	}
+
+	if s.upstream != nil {
+		return s.proxyHandle(request)
+	}
+
 	var exception *Exception
 	var data []byte
 
@@ -111,7 +163,12 @@ func (s *Server) handler() {
 	}
 }
 
-// Close stops listening to TCP/IP ports and closes serial ports.
+// Close stops listening to TCP/IP and UDP ports and closes serial ports.
+// It cancels the server context, which closes every accepted connection
+// at once, then waits up to DrainTimeout for the connection goroutines to
+// exit before closing any connections that remain. Requests already
+// handed to the handler are not waited for, so a response written after
+// its connection was closed is lost.
 func (s *Server) Close() {
 	for _, listen := range s.listeners {
 		listen.Close()
@@ -122,6 +179,27 @@ func (s *Server) Close() {
 		}
 	}
 
+	for _, pc := range s.packetConns {
+		pc.Close()
+	}
+
+	s.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		s.connsWG.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-time.After(s.DrainTimeout):
+		s.conns.Range(func(conn, _ interface{}) bool {
+			conn.(net.Conn).Close()
+			return true
+		})
+	}
+
 	close(s.portsCloseChan)
 	s.portsWG.Wait()
 
@@ -1,12 +1,39 @@
 package mbserver
 
 import (
+	"context"
+	"encoding/binary"
 	"io"
 	"log"
 	"net"
 	"strings"
+	"sync/atomic"
+	"time"
 )
 
+// MaxPDUSize bounds the MBAP length field (unit ID + function code +
+// data) a single request may declare; larger values are rejected as
+// malformed rather than causing an oversized read.
+const MaxPDUSize = 260
+
+// illegalDataValue is the raw Modbus exception code used in hand-built
+// responses to malformed frames, where no Framer exists yet to carry it.
+const illegalDataValue = 0x03
+
+// mbapExceptionResponse builds a minimal MBAP exception reply for a
+// request whose header could be parsed but whose PDU could not, so a
+// malformed frame gets a protocol-correct answer instead of a dropped
+// connection.
+func mbapExceptionResponse(header []byte, function uint8) []byte {
+	response := make([]byte, 9)
+	copy(response[0:4], header[0:4]) // transaction ID, protocol ID
+	binary.BigEndian.PutUint16(response[4:6], 3)
+	response[6] = header[6] // unit ID
+	response[7] = function | 0x80
+	response[8] = illegalDataValue
+	return response
+}
+
 func (s *Server) accept(listen net.Listener) error {
 	for {
 		conn, err := listen.Accept()
@@ -18,49 +45,120 @@ func (s *Server) accept(listen net.Listener) error {
 			return err
 		}
 
+		if s.RateLimiter != nil && !s.RateLimiter.Allow(conn.RemoteAddr()) {
+			conn.Close()
+			continue
+		}
+
+		if s.MaxConnections > 0 && int(atomic.LoadInt32(&s.connCount)) >= s.MaxConnections {
+			conn.Close()
+			continue
+		}
+
 		if s.ConnectionAcceptedEvent != nil {
 			for _, handler := range s.ConnectionAcceptedEvent {
 				handler(conn)
 			}
 		}
 
-		go func(conn net.Conn) {
-			defer func() {
-				conn.Close()
-				if s.ConnectionClosedEvent != nil {
-					for _, handler := range s.ConnectionClosedEvent {
-						handler(conn)
-					}
-				}
-			}()
-
-			for {
-				packet := make([]byte, 512)
-				bytesRead, err := conn.Read(packet)
-				if err != nil {
-					if err != io.EOF {
-						log.Printf("read error %v\n", err)
-					}
-					return
-				}
-				// Set the length of the packet to the number of read bytes.
-				packet = packet[:bytesRead]
-
-				frame, err := NewTCPFrame(packet)
-				if err != nil {
-					log.Printf("bad packet error %v\n", err)
-					return
-				}
-
-				request := &Request{conn, frame}
-
-				s.requestChan <- request
+		connCtx, cancel := context.WithCancel(s.ctx)
+		s.conns.Store(conn, cancel)
+		atomic.AddInt32(&s.connCount, 1)
+		s.connsWG.Add(1)
+
+		go s.serve(connCtx, conn)
+	}
+}
+
+// serve reads and dispatches requests from a single accepted connection
+// until it errors, goes idle past IdleTimeout, or ctx is canceled (by
+// Close canceling the server context).
+func (s *Server) serve(ctx context.Context, conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.conns.Delete(conn)
+		s.connRoles.Delete(conn)
+		atomic.AddInt32(&s.connCount, -1)
+		s.connsWG.Done()
+		if s.ConnectionClosedEvent != nil {
+			for _, handler := range s.ConnectionClosedEvent {
+				handler(conn)
+			}
+		}
+	}()
+
+	s.authorizeTLS(conn)
+
+	stopWatching := make(chan struct{})
+	defer close(stopWatching)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stopWatching:
+		}
+	}()
+
+	for {
+		deadline := s.IdleTimeout
+		if s.ReadTimeout > 0 && (deadline == 0 || s.ReadTimeout < deadline) {
+			deadline = s.ReadTimeout
+		}
+		if deadline > 0 {
+			conn.SetReadDeadline(time.Now().Add(deadline))
+		}
+
+		// Read the 7-byte MBAP header first so pipelined or
+		// fragmented frames on the same connection are parsed
+		// correctly instead of assuming one TCP segment equals one
+		// PDU.
+		header := make([]byte, 7)
+		if _, err := io.ReadFull(conn, header); err != nil {
+			if err != io.EOF {
+				log.Printf("read error %v\n", err)
+			}
+			return
+		}
+
+		length := binary.BigEndian.Uint16(header[4:6])
+		if length == 0 || int(length) > MaxPDUSize {
+			log.Printf("malformed MBAP length %d from %v\n", length, conn.RemoteAddr())
+			conn.Write(mbapExceptionResponse(header, 0))
+			continue
+		}
+
+		body := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, body); err != nil {
+			log.Printf("read error %v\n", err)
+			return
+		}
+
+		packet := append(header, body...)
+
+		frame, err := NewTCPFrame(packet)
+		if err != nil {
+			log.Printf("bad packet error %v\n", err)
+			var function uint8
+			if len(body) > 0 {
+				function = body[0]
 			}
-		}(conn)
+			conn.Write(mbapExceptionResponse(header, function))
+			continue
+		}
+
+		request := &Request{conn, frame}
+
+		select {
+		case s.requestChan <- request:
+		case <-ctx.Done():
+			return
+		}
 	}
 }
 
-// ListenTCP starts the Modbus server listening on "address:port".
+// ListenTCP starts the Modbus server listening on "address:port". It
+// returns once the listener is bound, or with the bind error if it could
+// not be; the accept loop then runs in the background until Close.
 func (s *Server) ListenTCP(addressPort string) (err error) {
 	listen, err := net.Listen("tcp", addressPort)
 	if err != nil {
@@ -0,0 +1,93 @@
+package mbserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"net"
+	"time"
+)
+
+// defaultHandshakeTimeout bounds how long ListenTLS waits for a client to
+// complete its TLS handshake when the server has no ReadTimeout set,
+// so an unresponsive client can't tie up a connection slot forever.
+const defaultHandshakeTimeout = 10 * time.Second
+
+// RoleOIDExtractor extracts an authorization role (e.g. "read-only" or
+// "operator") from a verified client certificate, so handlers can
+// enforce per-role access under the Modbus/TCP Security (TCP/802)
+// profile.
+type RoleOIDExtractor func(cert *x509.Certificate) (role string, err error)
+
+// ListenTLS starts the Modbus server listening on "address:port" under
+// the Modbus/TCP Security profile: tls.Listen wraps the socket, and
+// connections flow through the same accept/handler loop as plain TCP so
+// memory access stays serialized. If extractRole is set, it runs against
+// the first verified client certificate of each connection; the result
+// is available to handlers via ConnectionRole.
+func (s *Server) ListenTLS(addressPort string, cfg *tls.Config, extractRole RoleOIDExtractor) (err error) {
+	listen, err := tls.Listen("tcp", addressPort, cfg)
+	if err != nil {
+		log.Printf("Failed to ListenTLS: %v\n", err)
+		return err
+	}
+	if s.ServerStartedEvent != nil {
+		for _, handle := range s.ServerStartedEvent {
+			handle(listen)
+		}
+	}
+	s.listeners = append(s.listeners, listen)
+	s.roleExtractor = extractRole
+	go s.accept(listen)
+	return nil
+}
+
+// authorizeTLS completes conn's TLS handshake under a deadline — so a
+// client that never finishes it can't tie up a connection slot forever
+// — and, when ListenTLS was configured with a RoleOIDExtractor, records
+// the role extracted from its client certificate.
+func (s *Server) authorizeTLS(conn net.Conn) {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return
+	}
+
+	timeout := s.ReadTimeout
+	if timeout == 0 {
+		timeout = defaultHandshakeTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+
+	if err := tlsConn.Handshake(); err != nil {
+		log.Printf("TLS handshake failed for %v: %v\n", conn.RemoteAddr(), err)
+		return
+	}
+
+	if s.roleExtractor == nil {
+		return
+	}
+
+	state := tlsConn.ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+
+	role, err := s.roleExtractor(state.PeerCertificates[0])
+	if err != nil {
+		log.Printf("role extraction failed for %v: %v\n", conn.RemoteAddr(), err)
+		return
+	}
+	s.connRoles.Store(conn, role)
+}
+
+// ConnectionRole returns the role a RoleOIDExtractor derived for conn's
+// client certificate, if ListenTLS was configured with one and the
+// handshake succeeded.
+func (s *Server) ConnectionRole(conn net.Conn) (string, bool) {
+	v, ok := s.connRoles.Load(conn)
+	if !ok {
+		return "", false
+	}
+	return v.(string), true
+}
@@ -0,0 +1,111 @@
+package mbserver
+
+import (
+	"log"
+	"net"
+	"strings"
+	"time"
+)
+
+// udpConn adapts a single UDP peer address into a net.Conn so Modbus/UDP
+// datagrams can flow through the same Request/handler plumbing as TCP
+// and TLS connections.
+type udpConn struct {
+	pc     net.PacketConn
+	remote net.Addr
+}
+
+func (c *udpConn) Read(p []byte) (int, error) { return 0, net.ErrClosed }
+func (c *udpConn) Write(p []byte) (int, error) { return c.pc.WriteTo(p, c.remote) }
+func (c *udpConn) Close() error { return nil }
+func (c *udpConn) LocalAddr() net.Addr { return c.pc.LocalAddr() }
+func (c *udpConn) RemoteAddr() net.Addr { return c.remote }
+func (c *udpConn) SetDeadline(t time.Time) error      { return nil }
+func (c *udpConn) SetReadDeadline(t time.Time) error  { return nil }
+func (c *udpConn) SetWriteDeadline(t time.Time) error { return nil }
+
+// ListenUDP starts the Modbus server listening for Modbus/UDP datagrams
+// on "address:port". Each datagram is parsed as a single MBAP frame and
+// dispatched through the same handler loop as TCP/TLS, so memory access
+// stays serialized; the response is written back to the sending address.
+func (s *Server) ListenUDP(addressPort string) error {
+	pc, err := net.ListenPacket("udp", addressPort)
+	if err != nil {
+		log.Printf("Failed to ListenUDP: %v\n", err)
+		return err
+	}
+
+	s.packetConns = append(s.packetConns, pc)
+	go s.acceptUDP(pc)
+	return nil
+}
+
+func (s *Server) acceptUDP(pc net.PacketConn) {
+	for {
+		buf := make([]byte, MaxPDUSize+7)
+		n, addr, err := pc.ReadFrom(buf)
+		if err != nil {
+			if strings.Contains(err.Error(), "use of closed network connection") {
+				return
+			}
+			log.Printf("udp read error %v\n", err)
+			return
+		}
+
+		frame, err := NewTCPFrame(buf[:n])
+		if err != nil {
+			log.Printf("bad udp packet from %v: %v\n", addr, err)
+			continue
+		}
+
+		conn := s.udpPeer(pc, addr)
+		request := &Request{conn, frame}
+		s.requestChan <- request
+	}
+}
+
+// udpPeerState pairs a UDP pseudo-connection with the idle timer that
+// closes it, so the timer can be reset on every datagram rather than
+// firing once from creation.
+type udpPeerState struct {
+	conn  *udpConn
+	timer *time.Timer
+}
+
+// udpPeer returns the pseudo-connection for addr, creating it (and
+// firing ConnectionAcceptedEvent) the first time addr is seen. Every
+// datagram from addr resets its idle timer; ConnectionClosedEvent fires
+// once addr has gone IdleTimeout (30s by default) without a datagram.
+func (s *Server) udpPeer(pc net.PacketConn, addr net.Addr) *udpConn {
+	key := addr.String()
+	idle := s.IdleTimeout
+	if idle == 0 {
+		idle = 30 * time.Second
+	}
+
+	if v, ok := s.udpConns.Load(key); ok {
+		peer := v.(*udpPeerState)
+		peer.timer.Reset(idle)
+		return peer.conn
+	}
+
+	conn := &udpConn{pc: pc, remote: addr}
+	peer := &udpPeerState{conn: conn}
+	peer.timer = time.AfterFunc(idle, func() {
+		s.udpConns.Delete(key)
+		if s.ConnectionClosedEvent != nil {
+			for _, handler := range s.ConnectionClosedEvent {
+				handler(conn)
+			}
+		}
+	})
+	s.udpConns.Store(key, peer)
+
+	if s.ConnectionAcceptedEvent != nil {
+		for _, handler := range s.ConnectionAcceptedEvent {
+			handler(conn)
+		}
+	}
+
+	return conn
+}
@@ -0,0 +1,31 @@
+package mbserver
+
+import "sync/atomic"
+
+// RegisterUnit registers slave as the logical Modbus slave addressed by
+// unitID, letting a single listener host multiple virtual slaves (e.g. a
+// gateway standing in for several RTU devices, or a multi-device
+// simulator). Once any unit is registered, handle() routes every request
+// by unit ID instead of always treating the receiving server's own
+// memory as the target; requests for an unregistered unit ID get
+// GatewayTargetDeviceFailedToRespond.
+func (s *Server) RegisterUnit(unitID uint8, slave *Server) {
+	if _, loaded := s.units.Load(unitID); !loaded {
+		atomic.AddInt32(&s.unitCount, 1)
+	}
+	s.units.Store(unitID, slave)
+}
+
+// unitFor resolves the slave that should handle unitID. If no units have
+// been registered, every request is handled locally to preserve the
+// single-slave behavior of a server that never calls RegisterUnit.
+func (s *Server) unitFor(unitID uint8) (*Server, bool) {
+	if atomic.LoadInt32(&s.unitCount) == 0 {
+		return s, true
+	}
+	slave, ok := s.units.Load(unitID)
+	if !ok {
+		return nil, false
+	}
+	return slave.(*Server), true
+}